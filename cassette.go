@@ -0,0 +1,171 @@
+package webmock
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// cassetteRequest describes the request half of a recorded interaction.
+type cassetteRequest struct {
+	Method    string `yaml:"method"`
+	Path      string `yaml:"path"`
+	Query     string `yaml:"query"`
+	PathRegex string `yaml:"path_regex"`
+	BodyJSON  string `yaml:"body_json"`
+
+	// Body is the exact request body this interaction was recorded
+	// against; replay requires an incoming request's body to match it.
+	Body string `yaml:"body"`
+
+	// Headers holds the request headers captured at record time, with
+	// any Server.Record(WithRedactedHeaders(...)) values scrubbed.
+	Headers map[string]string `yaml:"headers"`
+
+	// MatchHeaders lists which of Headers' keys must match exactly for
+	// an incoming request to be considered a replay of this interaction.
+	MatchHeaders []string `yaml:"match_headers"`
+}
+
+// cassetteResponse describes the response half of a recorded interaction.
+type cassetteResponse struct {
+	Status   int               `yaml:"status"`
+	Body     string            `yaml:"body"`
+	Headers  map[string]string `yaml:"headers"`
+	Template bool              `yaml:"template"`
+	DelayMs  int               `yaml:"delay_ms"`
+	Priority int               `yaml:"priority"`
+}
+
+// cassetteInteraction is one request/response pair in a cassette file.
+type cassetteInteraction struct {
+	Request  cassetteRequest  `yaml:"request"`
+	Response cassetteResponse `yaml:"response"`
+}
+
+// LoadCassettes registers stubs from the YAML cassette file(s) at path.
+// path may be a single file or a directory, in which case every *.yml
+// and *.yaml file inside it is loaded.
+func (s *Server) LoadCassettes(path string) error {
+	files, err := cassetteFiles(path)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if err := s.loadCassetteFile(file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func cassetteFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if ext != ".yml" && ext != ".yaml" {
+			continue
+		}
+		files = append(files, filepath.Join(path, entry.Name()))
+	}
+	return files, nil
+}
+
+func (s *Server) loadCassetteFile(file string) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	var interactions []cassetteInteraction
+	if err := yaml.Unmarshal(data, &interactions); err != nil {
+		return err
+	}
+
+	for _, interaction := range interactions {
+		st, err := interactionToStub(interaction)
+		if err != nil {
+			return err
+		}
+		s.addStub(st)
+	}
+	return nil
+}
+
+func interactionToStub(interaction cassetteInteraction) (*stub, error) {
+	query, _ := url.ParseQuery(interaction.Request.Query)
+
+	st := &stub{
+		method:   strings.ToUpper(interaction.Request.Method),
+		path:     interaction.Request.Path,
+		query:    query,
+		status:   interaction.Response.Status,
+		body:     interaction.Response.Body,
+		header:   interaction.Response.Headers,
+		priority: interaction.Response.Priority,
+	}
+
+	if interaction.Request.Body != "" {
+		st.reqBody = interaction.Request.Body
+		st.hasReqBody = true
+	}
+
+	if interaction.Request.PathRegex != "" {
+		re, err := regexp.Compile(interaction.Request.PathRegex)
+		if err != nil {
+			return nil, err
+		}
+		st.pathRegex = re
+	}
+	if len(interaction.Request.MatchHeaders) > 0 {
+		st.headers = make(map[string][]string, len(interaction.Request.MatchHeaders))
+		for _, name := range interaction.Request.MatchHeaders {
+			key := http.CanonicalHeaderKey(name)
+			st.headers[key] = []string{interaction.Request.Headers[key]}
+		}
+	}
+	if interaction.Request.BodyJSON != "" {
+		var fragment map[string]interface{}
+		if json.Unmarshal([]byte(interaction.Request.BodyJSON), &fragment) == nil {
+			st.jsonBody = fragment
+		}
+	}
+	if interaction.Response.DelayMs > 0 {
+		st.delay = time.Duration(interaction.Response.DelayMs) * time.Millisecond
+	}
+	if interaction.Response.Template {
+		tmpl, err := template.New("cassette").Parse(interaction.Response.Body)
+		if err == nil {
+			st.tmpl = tmpl
+		}
+	}
+
+	return st, nil
+}
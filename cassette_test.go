@@ -0,0 +1,171 @@
+package webmock_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hlcfan/webmock"
+)
+
+func TestLoadCassettesWithPathRegexBodyJSONAndPriority(t *testing.T) {
+	cassette := `
+- request:
+    method: GET
+    path_regex: "^/users/[0-9]+$"
+  response:
+    status: 200
+    body: catch-all
+- request:
+    method: POST
+    path: /items
+    body_json: '{"a":1}'
+  response:
+    status: 200
+    body: matched
+- request:
+    method: GET
+    path: /users/42
+  response:
+    status: 200
+    body: specific
+    priority: 1
+`
+	path := filepath.Join(t.TempDir(), "cassette.yml")
+	if err := ioutil.WriteFile(path, []byte(cassette), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	server := webmock.New()
+	server.Start()
+
+	if err := server.LoadCassettes(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	resp, err := http.Get(server.URL() + "/users/42")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	if string(body) != "specific" {
+		t.Errorf("expected the higher-priority stub to win over the path_regex match, want: %s, got: %s", "specific", string(body))
+	}
+
+	resp, err = http.Get(server.URL() + "/users/abc")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected path_regex stub to reject a non-numeric user id, want status: %d, got: %d", http.StatusNotFound, resp.StatusCode)
+	}
+
+	resp, err = http.Get(server.URL() + "/users/7")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ = ioutil.ReadAll(resp.Body)
+	if string(body) != "catch-all" {
+		t.Errorf("expected path_regex stub to match a numeric user id without the higher-priority override, want: %s, got: %s", "catch-all", string(body))
+	}
+
+	resp, err = http.Post(server.URL()+"/items", "application/json", strings.NewReader(`{"a":1,"b":2}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ = ioutil.ReadAll(resp.Body)
+	if string(body) != "matched" {
+		t.Errorf("expected body_json stub to match a superset body, want: %s, got: %s", "matched", string(body))
+	}
+}
+
+func TestLoadCassettesWithTemplateAndDelay(t *testing.T) {
+	cassette := `
+- request:
+    method: GET
+    path: /greet
+  response:
+    status: 200
+    body: 'hello, {{.Query.name}}'
+    template: true
+- request:
+    method: GET
+    path: /slow
+  response:
+    status: 200
+    body: done
+    delay_ms: 200
+`
+	path := filepath.Join(t.TempDir(), "cassette.yml")
+	if err := ioutil.WriteFile(path, []byte(cassette), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	server := webmock.New()
+	server.Start()
+
+	if err := server.LoadCassettes(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	resp, err := http.Get(server.URL() + "/greet?name=Alex")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	want := "hello, Alex"
+	if string(body) != want {
+		t.Errorf("expected a cassette-loaded template to be rendered, want: %s, got: %s", want, string(body))
+	}
+
+	start := time.Now()
+	resp, err = http.Get(server.URL() + "/slow")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("expected a cassette-loaded delay_ms to delay the response at least 200ms, took: %s", elapsed)
+	}
+
+	body, _ = ioutil.ReadAll(resp.Body)
+	if string(body) != "done" {
+		t.Errorf("unexpected body, want: %s, got: %s", "done", string(body))
+	}
+}
+
+func TestLoadCassettesWithInvalidPathRegexReturnsError(t *testing.T) {
+	cassette := `
+- request:
+    method: GET
+    path_regex: "("
+  response:
+    status: 200
+    body: broken
+`
+	path := filepath.Join(t.TempDir(), "cassette.yml")
+	if err := ioutil.WriteFile(path, []byte(cassette), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	server := webmock.New()
+	server.Start()
+
+	if err := server.LoadCassettes(path); err == nil {
+		t.Errorf("expected an invalid path_regex to return an error instead of panicking")
+	}
+}
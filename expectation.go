@@ -0,0 +1,165 @@
+package webmock
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// recordedRequest is a buffered snapshot of an incoming request, kept so
+// its body can be read more than once.
+type recordedRequest struct {
+	request *http.Request
+	body    []byte
+}
+
+// recordRequest buffers r's body and appends a snapshot to the request
+// history, restoring r.Body so the rest of the handler chain can still
+// read it.
+func (s *Server) recordRequest(r *http.Request) {
+	var bodyBytes []byte
+	if r.Body != nil {
+		bodyBytes, _ = ioutil.ReadAll(r.Body)
+		r.Body.Close()
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(bodyBytes))
+
+	clone := r.Clone(r.Context())
+	clone.Body = ioutil.NopCloser(bytes.NewReader(bodyBytes))
+
+	s.requestsMu.Lock()
+	s.requests = append(s.requests, recordedRequest{request: clone, body: bodyBytes})
+	s.requestsMu.Unlock()
+}
+
+// Requests returns every request the server has received so far, in
+// order, with bodies buffered so they can be read again.
+func (s *Server) Requests() []*http.Request {
+	s.requestsMu.Lock()
+	defer s.requestsMu.Unlock()
+
+	reqs := make([]*http.Request, len(s.requests))
+	for i, rr := range s.requests {
+		reqs[i] = cloneRecordedRequest(rr)
+	}
+	return reqs
+}
+
+// RequestsFor returns the received requests matching method and path.
+func (s *Server) RequestsFor(method, path string) []*http.Request {
+	s.requestsMu.Lock()
+	defer s.requestsMu.Unlock()
+
+	var reqs []*http.Request
+	for _, rr := range s.requests {
+		if strings.EqualFold(rr.request.Method, method) && rr.request.URL.Path == path {
+			reqs = append(reqs, cloneRecordedRequest(rr))
+		}
+	}
+	return reqs
+}
+
+func cloneRecordedRequest(rr recordedRequest) *http.Request {
+	clone := rr.request.Clone(rr.request.Context())
+	clone.Body = ioutil.NopCloser(bytes.NewReader(rr.body))
+	return clone
+}
+
+// expectation describes how many times a request matching a pattern is
+// expected to be received.
+type expectation struct {
+	matcher *stub
+	times   int
+
+	body    string
+	hasBody bool
+}
+
+// ExpectOption customizes an expectation registered via Server.Expect.
+type ExpectOption func(*expectation)
+
+// Times sets the exact number of times the expectation must be matched.
+// Defaults to 1 if not given.
+func Times(n int) ExpectOption {
+	return func(e *expectation) {
+		e.times = n
+	}
+}
+
+// WithBody restricts the expectation to requests with an exact matching
+// body.
+func WithBody(body string) ExpectOption {
+	return func(e *expectation) {
+		e.body = body
+		e.hasBody = true
+	}
+}
+
+// Expect registers an expectation that method and urlPath are called the
+// configured number of times (once, by default). Call VerifyExpectations
+// to check it was met.
+func (s *Server) Expect(method, urlPath string, opts ...ExpectOption) *expectation {
+	parsed, err := url.Parse(urlPath)
+	if err != nil {
+		panic(err)
+	}
+
+	e := &expectation{
+		matcher: &stub{
+			method: strings.ToUpper(method),
+			path:   parsed.Path,
+			query:  parsed.Query(),
+		},
+		times: 1,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	s.expMu.Lock()
+	s.expectations = append(s.expectations, e)
+	s.expMu.Unlock()
+
+	return e
+}
+
+// VerifyExpectations fails t if any registered expectation was not
+// matched the expected number of times.
+func (s *Server) VerifyExpectations(t *testing.T) {
+	t.Helper()
+
+	for _, msg := range s.mismatches() {
+		t.Error(msg)
+	}
+}
+
+// mismatches returns a description of every registered expectation that
+// wasn't matched the expected number of times.
+func (s *Server) mismatches() []string {
+	s.expMu.Lock()
+	expectations := append([]*expectation(nil), s.expectations...)
+	s.expMu.Unlock()
+
+	s.requestsMu.Lock()
+	requests := append([]recordedRequest(nil), s.requests...)
+	s.requestsMu.Unlock()
+
+	var msgs []string
+	for _, e := range expectations {
+		got := 0
+		for _, rr := range requests {
+			if matchStub(e.matcher, rr.request) && (!e.hasBody || string(rr.body) == e.body) {
+				got++
+			}
+		}
+
+		if got != e.times {
+			msgs = append(msgs, fmt.Sprintf("expected %s %s to be called %d time(s), got %d", e.matcher.method, e.matcher.path, e.times, got))
+		}
+	}
+	return msgs
+}
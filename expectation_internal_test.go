@@ -0,0 +1,30 @@
+package webmock
+
+import (
+	"net/http"
+	"testing"
+)
+
+// TestMismatchesReportsUnmetExpectations exercises VerifyExpectations'
+// failure path directly via mismatches, since a *testing.T can't assert
+// that another *testing.T would have failed (a nested t.Run's failure
+// always propagates to its parent, regardless of its returned bool).
+func TestMismatchesReportsUnmetExpectations(t *testing.T) {
+	server := New()
+	server.Start()
+	defer server.httpServer.Close()
+
+	server.Stub("GET", "/health", "ok")
+	server.Expect("GET", "/health", Times(2))
+
+	resp, err := http.Get(server.URL() + "/health")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+
+	msgs := server.mismatches()
+	if len(msgs) != 1 {
+		t.Fatalf("expected 1 mismatch when called once instead of twice, got %d: %v", len(msgs), msgs)
+	}
+}
@@ -0,0 +1,54 @@
+package webmock_test
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/hlcfan/webmock"
+)
+
+func TestServerExpect(t *testing.T) {
+	client := &http.Client{}
+
+	t.Run("passes when a stub is called the expected number of times", func(t *testing.T) {
+		server := webmock.New()
+		server.Start()
+		server.Stub("POST", "/orders", `{"id":1}`)
+		server.Expect("POST", "/orders", webmock.Times(2), webmock.WithBody(`{"id":1}`))
+
+		for i := 0; i < 2; i++ {
+			req, err := http.NewRequest("POST", server.URL()+"/orders", bytes.NewBufferString(`{"id":1}`))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			resp.Body.Close()
+		}
+
+		server.VerifyExpectations(t)
+	})
+
+	t.Run("Requests and RequestsFor return buffered request history", func(t *testing.T) {
+		server := webmock.New()
+		server.Start()
+		server.Stub("GET", "/ping", "pong")
+
+		if _, err := http.Get(server.URL() + "/ping"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got := len(server.Requests()); got != 1 {
+			t.Errorf("unexpected request count, want: %d, got: %d", 1, got)
+		}
+		if got := len(server.RequestsFor("GET", "/ping")); got != 1 {
+			t.Errorf("unexpected request count for /ping, want: %d, got: %d", 1, got)
+		}
+		if got := len(server.RequestsFor("GET", "/missing")); got != 0 {
+			t.Errorf("unexpected request count for /missing, want: %d, got: %d", 0, got)
+		}
+	})
+}
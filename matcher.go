@@ -0,0 +1,233 @@
+package webmock
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// WithPathRegex matches the stub against any path satisfying re, instead
+// of requiring an exact path.
+func WithPathRegex(re string) Option {
+	compiled := regexp.MustCompile(re)
+	return func(st *stub) {
+		st.pathRegex = compiled
+	}
+}
+
+// WithQueryRegex matches each named query parameter against its regex,
+// in addition to (or instead of) any exact query set on the stub.
+func WithQueryRegex(patterns map[string]string) Option {
+	return func(st *stub) {
+		if st.queryRegex == nil {
+			st.queryRegex = make(map[string]*regexp.Regexp, len(patterns))
+		}
+		for k, re := range patterns {
+			st.queryRegex[k] = regexp.MustCompile(re)
+		}
+	}
+}
+
+// WithHeaderRegex matches each named header against its regex.
+func WithHeaderRegex(patterns map[string]string) Option {
+	return func(st *stub) {
+		if st.headerRegex == nil {
+			st.headerRegex = make(map[string]*regexp.Regexp, len(patterns))
+		}
+		for k, re := range patterns {
+			st.headerRegex[http.CanonicalHeaderKey(k)] = regexp.MustCompile(re)
+		}
+	}
+}
+
+// WithJSONBody matches requests whose JSON body is a superset of
+// fragment, i.e. every field in fragment is present with an equal value;
+// extra fields in the actual body are ignored.
+func WithJSONBody(fragment string) Option {
+	var parsed map[string]interface{}
+	if err := json.Unmarshal([]byte(fragment), &parsed); err != nil {
+		panic(err)
+	}
+
+	return func(st *stub) {
+		st.jsonBody = parsed
+	}
+}
+
+// WithPriority makes a stub win over lower-priority stubs that also
+// match a request, regardless of registration order. Defaults to 0.
+func WithPriority(p int) Option {
+	return func(st *stub) {
+		st.priority = p
+	}
+}
+
+func matchStub(st *stub, r *http.Request) bool {
+	if st.method != strings.ToUpper(r.Method) {
+		return false
+	}
+	if !pathMatches(st, r.URL.Path) {
+		return false
+	}
+	if !queryMatches(st.query, r.URL.Query()) {
+		return false
+	}
+	if !queryRegexMatches(st.queryRegex, r.URL.Query()) {
+		return false
+	}
+	if !headersMatch(st.headers, r.Header) {
+		return false
+	}
+	if !headerRegexMatches(st.headerRegex, r.Header) {
+		return false
+	}
+	if !jsonBodyMatches(st.jsonBody, r) {
+		return false
+	}
+	if st.hasReqBody && !bodyMatches(st.reqBody, r) {
+		return false
+	}
+	return true
+}
+
+// bodyMatches reports whether r's body is exactly want, restoring r.Body
+// afterwards so downstream handling (proxying, templating) can still
+// read it.
+func bodyMatches(want string, r *http.Request) bool {
+	var bodyBytes []byte
+	if r.Body != nil {
+		bodyBytes, _ = ioutil.ReadAll(r.Body)
+		r.Body.Close()
+	}
+	r.Body = ioutil.NopCloser(strings.NewReader(string(bodyBytes)))
+
+	return string(bodyBytes) == want
+}
+
+func pathMatches(st *stub, path string) bool {
+	if st.pathRegex != nil {
+		return st.pathRegex.MatchString(path)
+	}
+	return st.path == path
+}
+
+func queryMatches(want, got url.Values) bool {
+	if len(want) == 0 {
+		return true
+	}
+	if len(want) != len(got) {
+		return false
+	}
+	for k, vs := range want {
+		if strings.Join(got[k], ",") != strings.Join(vs, ",") {
+			return false
+		}
+	}
+	return true
+}
+
+func queryRegexMatches(patterns map[string]*regexp.Regexp, got url.Values) bool {
+	for k, re := range patterns {
+		if !re.MatchString(got.Get(k)) {
+			return false
+		}
+	}
+	return true
+}
+
+func headersMatch(want map[string][]string, got http.Header) bool {
+	for k, vs := range want {
+		if strings.Join(got[k], ",") != strings.Join(vs, ",") {
+			return false
+		}
+	}
+	return true
+}
+
+func headerRegexMatches(patterns map[string]*regexp.Regexp, got http.Header) bool {
+	for k, re := range patterns {
+		if !re.MatchString(got.Get(k)) {
+			return false
+		}
+	}
+	return true
+}
+
+func jsonBodyMatches(fragment map[string]interface{}, r *http.Request) bool {
+	if fragment == nil {
+		return true
+	}
+
+	var bodyBytes []byte
+	if r.Body != nil {
+		bodyBytes, _ = ioutil.ReadAll(r.Body)
+		r.Body.Close()
+	}
+	r.Body = ioutil.NopCloser(strings.NewReader(string(bodyBytes)))
+
+	var actual map[string]interface{}
+	if err := json.Unmarshal(bodyBytes, &actual); err != nil {
+		return false
+	}
+
+	return jsonSubset(fragment, actual)
+}
+
+// jsonSubset reports whether every key/value in want is present with an
+// equal value in got. Nested objects are compared recursively; extra
+// fields in got (at any level) are ignored.
+func jsonSubset(want, got map[string]interface{}) bool {
+	for k, wantVal := range want {
+		gotVal, ok := got[k]
+		if !ok {
+			return false
+		}
+
+		wantMap, wantIsMap := wantVal.(map[string]interface{})
+		gotMap, gotIsMap := gotVal.(map[string]interface{})
+		if wantIsMap && gotIsMap {
+			if !jsonSubset(wantMap, gotMap) {
+				return false
+			}
+			continue
+		}
+
+		if !reflect.DeepEqual(wantVal, gotVal) {
+			return false
+		}
+	}
+	return true
+}
+
+// constraintCount is a rough measure of how specific a stub's matchers
+// are, used to break ties between same-priority stubs.
+func (st *stub) constraintCount() int {
+	n := len(st.headers) + len(st.query) + len(st.queryRegex) + len(st.headerRegex)
+	if st.pathRegex != nil {
+		n++
+	}
+	if st.jsonBody != nil {
+		n++
+	}
+	if st.hasReqBody {
+		n++
+	}
+	return n
+}
+
+// moreSpecificThan reports whether st should win over other when both
+// match the same request: higher priority first, then more constraints,
+// then earlier registration (FIFO).
+func (st *stub) moreSpecificThan(other *stub) bool {
+	if st.priority != other.priority {
+		return st.priority > other.priority
+	}
+	if c1, c2 := st.constraintCount(), other.constraintCount(); c1 != c2 {
+		return c1 > c2
+	}
+	return st.seq < other.seq
+}
@@ -0,0 +1,143 @@
+package webmock_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/hlcfan/webmock"
+)
+
+func TestServerWithPathRegex(t *testing.T) {
+	server := webmock.New()
+	server.Start()
+
+	server.Stub("GET", "", "user", webmock.WithPathRegex(`^/users/[0-9]+$`))
+
+	resp, err := http.Get(server.URL() + "/users/42")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("unexpected status for /users/42, want: %d, got: %d", http.StatusOK, resp.StatusCode)
+	}
+	resp.Body.Close()
+
+	resp, err = http.Get(server.URL() + "/users/abc")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unexpected status for /users/abc, want: %d, got: %d", http.StatusNotFound, resp.StatusCode)
+	}
+	resp.Body.Close()
+}
+
+func TestServerWithJSONBody(t *testing.T) {
+	server := webmock.New()
+	server.Start()
+
+	server.Stub("POST", "/items", "matched", webmock.WithJSONBody(`{"a":1}`))
+
+	resp, err := http.Post(server.URL()+"/items", "application/json", bytes.NewBufferString(`{"a":1,"b":2}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("unexpected status, want: %d, got: %d", http.StatusOK, resp.StatusCode)
+	}
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	if string(body) != "matched" {
+		t.Errorf("unexpected body, want: %s, got: %s", "matched", string(body))
+	}
+
+	resp, err = http.Post(server.URL()+"/items", "application/json", bytes.NewBufferString(`{"a":2}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unexpected status for non-matching body, want: %d, got: %d", http.StatusNotFound, resp.StatusCode)
+	}
+}
+
+func TestServerWithQueryRegex(t *testing.T) {
+	server := webmock.New()
+	server.Start()
+
+	server.Stub("GET", "/search", "results", webmock.WithQueryRegex(map[string]string{"q": `^[a-z]+$`}))
+
+	resp, err := http.Get(server.URL() + "/search?q=golang")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("unexpected status for q=golang, want: %d, got: %d", http.StatusOK, resp.StatusCode)
+	}
+	resp.Body.Close()
+
+	resp, err = http.Get(server.URL() + "/search?q=123")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unexpected status for q=123, want: %d, got: %d", http.StatusNotFound, resp.StatusCode)
+	}
+	resp.Body.Close()
+}
+
+func TestServerWithHeaderRegex(t *testing.T) {
+	server := webmock.New()
+	server.Start()
+
+	server.Stub("GET", "/versioned", "ok", webmock.WithHeaderRegex(map[string]string{"X-Api-Version": `^v[0-9]+$`}))
+
+	req, err := http.NewRequest("GET", server.URL()+"/versioned", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	req.Header.Set("X-Api-Version", "v2")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("unexpected status for X-Api-Version=v2, want: %d, got: %d", http.StatusOK, resp.StatusCode)
+	}
+	resp.Body.Close()
+
+	req.Header.Set("X-Api-Version", "beta")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unexpected status for X-Api-Version=beta, want: %d, got: %d", http.StatusNotFound, resp.StatusCode)
+	}
+	resp.Body.Close()
+}
+
+func TestServerWithPriority(t *testing.T) {
+	server := webmock.New()
+	server.Start()
+
+	server.Stub("GET", "", "catch-all", webmock.WithPathRegex(`^/api/.*$`))
+	server.Stub("GET", "/api/health", "specific", webmock.WithPriority(1))
+
+	resp, err := http.Get(server.URL() + "/api/health")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	if string(body) != "specific" {
+		t.Errorf("expected higher-priority stub to win, want: %s, got: %s", "specific", string(body))
+	}
+}
@@ -0,0 +1,43 @@
+package webmock
+
+import (
+	"net/http"
+	"strings"
+)
+
+// WithHeaders restricts a stub to requests carrying the given header,
+// expressed as a single "Key: value" line (e.g. "Accept-Encoding: gzip,deflate").
+func WithHeaders(headerLine string) Option {
+	return func(st *stub) {
+		parts := strings.SplitN(headerLine, ":", 2)
+		if len(parts) != 2 {
+			return
+		}
+
+		key := http.CanonicalHeaderKey(strings.TrimSpace(parts[0]))
+		value := strings.TrimSpace(parts[1])
+
+		if st.headers == nil {
+			st.headers = make(map[string][]string)
+		}
+		st.headers[key] = []string{value}
+	}
+}
+
+// WithResponse overrides the status, body, and headers returned by a stub.
+func WithResponse(status int, body string, headers map[string]string) Option {
+	return func(st *stub) {
+		st.status = status
+		st.body = body
+
+		if len(headers) == 0 {
+			return
+		}
+		if st.header == nil {
+			st.header = make(map[string]string)
+		}
+		for k, v := range headers {
+			st.header[k] = v
+		}
+	}
+}
@@ -0,0 +1,327 @@
+package webmock
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// RecordMode controls how Server.Record behaves when a request doesn't
+// match an already-loaded stub.
+type RecordMode string
+
+const (
+	// RecordModeOnce records new interactions only the first time a
+	// cassette is created; once the cassette exists, unmatched requests
+	// are treated as failures instead of being proxied again.
+	RecordModeOnce RecordMode = "once"
+
+	// RecordModeNewEpisodes replays existing interactions and proxies
+	// (and records) any request that doesn't match one.
+	RecordModeNewEpisodes RecordMode = "new_episodes"
+
+	// RecordModeNone never proxies; only interactions already present in
+	// the cassette are served.
+	RecordModeNone RecordMode = "none"
+)
+
+// RecordOption customizes a recorder configured via Server.Record.
+type RecordOption func(*recorder)
+
+// WithRecordMode sets the record mode. Defaults to RecordModeOnce.
+func WithRecordMode(mode RecordMode) RecordOption {
+	return func(rec *recorder) {
+		rec.mode = mode
+	}
+}
+
+// WithRedactedHeaders replaces the value of the given headers with
+// "[REDACTED]" before an interaction is persisted to the cassette.
+func WithRedactedHeaders(headers ...string) RecordOption {
+	return func(rec *recorder) {
+		for _, h := range headers {
+			rec.redact[http.CanonicalHeaderKey(h)] = true
+		}
+	}
+}
+
+// WithKeyHeaders includes the given headers (in addition to method, path
+// and query) when computing the dedup key for a recorded interaction.
+func WithKeyHeaders(headers ...string) RecordOption {
+	return func(rec *recorder) {
+		for _, h := range headers {
+			rec.keyHeaders = append(rec.keyHeaders, http.CanonicalHeaderKey(h))
+		}
+	}
+}
+
+// recorder proxies unmatched requests to an upstream and appends the
+// captured request/response pair to a cassette file.
+type recorder struct {
+	upstream     *url.URL
+	cassettePath string
+	mode         RecordMode
+	redact       map[string]bool
+	keyHeaders   []string
+
+	mu          sync.Mutex
+	seenKeys    map[string]bool
+	fileExisted bool
+}
+
+// Record configures the server to transparently proxy requests that don't
+// match a stub to upstream, and to append the captured interaction to the
+// YAML cassette at cassettePath. Defaults to RecordModeOnce.
+func (s *Server) Record(upstream, cassettePath string, opts ...RecordOption) error {
+	u, err := url.Parse(upstream)
+	if err != nil {
+		return err
+	}
+
+	rec := &recorder{
+		upstream:     u,
+		cassettePath: cassettePath,
+		mode:         RecordModeOnce,
+		redact:       make(map[string]bool),
+		seenKeys:     make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(rec)
+	}
+
+	if _, err := os.Stat(cassettePath); err == nil {
+		rec.fileExisted = true
+
+		if err := s.LoadCassettes(cassettePath); err != nil {
+			return err
+		}
+
+		interactions, err := readCassette(cassettePath)
+		if err != nil {
+			return err
+		}
+		for _, interaction := range interactions {
+			rec.seenKeys[interactionKey(interaction)] = true
+		}
+	}
+
+	s.recorder = rec
+	return nil
+}
+
+// tryRecord handles a request that didn't match any stub. It returns true
+// if it wrote the response (the proxied upstream response, or a 502 when
+// the upstream can't be reached), and false if it declined to proxy, in
+// which case nothing has been written.
+func (s *Server) tryRecord(w http.ResponseWriter, r *http.Request) bool {
+	rec := s.recorder
+	if rec == nil {
+		return false
+	}
+
+	if rec.mode == RecordModeNone {
+		return false
+	}
+	if rec.mode == RecordModeOnce && rec.fileExisted {
+		return false
+	}
+
+	var bodyBytes []byte
+	if r.Body != nil {
+		bodyBytes, _ = ioutil.ReadAll(r.Body)
+		r.Body.Close()
+	}
+
+	key := requestKey(r.Method, r.URL, bodyBytes, rec.keyHeaders, r.Header)
+
+	rec.mu.Lock()
+	alreadySeen := rec.seenKeys[key]
+	rec.mu.Unlock()
+	if alreadySeen {
+		return false
+	}
+
+	resp, err := proxyRequest(rec.upstream, r, bodyBytes)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return true
+	}
+
+	for k, vs := range resp.header {
+		for _, v := range vs {
+			w.Header().Add(k, v)
+		}
+	}
+	w.WriteHeader(resp.statusCode)
+	w.Write(resp.body)
+
+	// interaction carries the real, unredacted request data so the
+	// in-memory replay stub can match on it (including any configured
+	// key headers and the body); the cassette on disk only ever sees the
+	// redacted copy.
+	interaction := buildInteraction(r, bodyBytes, resp, rec.keyHeaders)
+
+	if st, err := interactionToStub(interaction); err == nil {
+		s.addStub(st)
+	}
+
+	rec.mu.Lock()
+	rec.seenKeys[key] = true
+	appendCassette(rec.cassettePath, redactInteraction(interaction, rec.redact))
+	rec.mu.Unlock()
+
+	return true
+}
+
+type proxiedResponse struct {
+	statusCode int
+	header     http.Header
+	body       []byte
+}
+
+func proxyRequest(upstream *url.URL, r *http.Request, body []byte) (*proxiedResponse, error) {
+	target := *upstream
+	target.Path = strings.TrimRight(upstream.Path, "/") + r.URL.Path
+	target.RawQuery = r.URL.RawQuery
+
+	outReq, err := http.NewRequest(r.Method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	outReq.Header = r.Header.Clone()
+
+	resp, err := http.DefaultClient.Do(outReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &proxiedResponse{
+		statusCode: resp.StatusCode,
+		header:     resp.Header,
+		body:       respBody,
+	}, nil
+}
+
+func requestKey(method string, u *url.URL, body []byte, keyHeaders []string, header http.Header) string {
+	var parts []string
+	parts = append(parts, strings.ToUpper(method), u.Path, u.Query().Encode())
+
+	for _, h := range keyHeaders {
+		parts = append(parts, h+"="+strings.Join(header[h], ","))
+	}
+	parts = append(parts, string(body))
+
+	return strings.Join(parts, "|")
+}
+
+// buildInteraction captures the full, unredacted request/response pair so
+// it can both drive in-memory replay matching (method, path, query, the
+// configured key headers, and the body) and be persisted to disk.
+func buildInteraction(r *http.Request, body []byte, resp *proxiedResponse, keyHeaders []string) cassetteInteraction {
+	return cassetteInteraction{
+		Request: cassetteRequest{
+			Method:       r.Method,
+			Path:         r.URL.Path,
+			Query:        r.URL.Query().Encode(),
+			Body:         string(body),
+			Headers:      joinHeaderValues(r.Header),
+			MatchHeaders: keyHeaders,
+		},
+		Response: cassetteResponse{
+			Status:  resp.statusCode,
+			Body:    string(resp.body),
+			Headers: joinHeaderValues(resp.header),
+		},
+	}
+}
+
+// redactInteraction returns a copy of interaction with any header named
+// in redact replaced by "[REDACTED]" in both the request and response
+// headers, for safe persistence to the cassette.
+func redactInteraction(interaction cassetteInteraction, redact map[string]bool) cassetteInteraction {
+	interaction.Request.Headers = redactHeaders(interaction.Request.Headers, redact)
+	interaction.Response.Headers = redactHeaders(interaction.Response.Headers, redact)
+	return interaction
+}
+
+func redactHeaders(headers map[string]string, redact map[string]bool) map[string]string {
+	out := make(map[string]string, len(headers))
+	for k, v := range headers {
+		if redact[http.CanonicalHeaderKey(k)] {
+			v = "[REDACTED]"
+		}
+		out[k] = v
+	}
+	return out
+}
+
+func joinHeaderValues(header http.Header) map[string]string {
+	out := make(map[string]string, len(header))
+	for k, vs := range header {
+		out[k] = strings.Join(vs, ",")
+	}
+	return out
+}
+
+func interactionKey(interaction cassetteInteraction) string {
+	values, _ := url.ParseQuery(interaction.Request.Query)
+
+	parts := []string{
+		strings.ToUpper(interaction.Request.Method),
+		interaction.Request.Path,
+		values.Encode(),
+	}
+	for _, h := range interaction.Request.MatchHeaders {
+		key := http.CanonicalHeaderKey(h)
+		parts = append(parts, key+"="+interaction.Request.Headers[key])
+	}
+	parts = append(parts, interaction.Request.Body)
+
+	return strings.Join(parts, "|")
+}
+
+func readCassette(path string) ([]cassetteInteraction, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+
+	var interactions []cassetteInteraction
+	if err := yaml.Unmarshal(data, &interactions); err != nil {
+		return nil, err
+	}
+	return interactions, nil
+}
+
+func appendCassette(path string, interaction cassetteInteraction) error {
+	interactions, err := readCassette(path)
+	if err != nil {
+		return err
+	}
+	interactions = append(interactions, interaction)
+
+	data, err := yaml.Marshal(interactions)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,126 @@
+package webmock_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hlcfan/webmock"
+)
+
+func TestServerRecord(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+		w.Write(append([]byte("echo: "), body...))
+	}))
+	defer upstream.Close()
+
+	dir := t.TempDir()
+	cassettePath := filepath.Join(dir, "cassette.yml")
+
+	t.Run("records unmatched requests to the cassette on first run and redacts the client's Authorization header", func(t *testing.T) {
+		server := webmock.New()
+		server.Start()
+
+		if err := server.Record(upstream.URL, cassettePath, webmock.WithRedactedHeaders("Authorization")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		req, err := http.NewRequest("POST", server.URL()+"/proxy-me", bytes.NewBufferString("hello"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		req.Header.Set("Authorization", "Bearer secret-token")
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("unexpected status, want: %d, got: %d", http.StatusOK, resp.StatusCode)
+		}
+
+		body, _ := ioutil.ReadAll(resp.Body)
+		if string(body) != "echo: hello" {
+			t.Errorf("unexpected body, want: %s, got: %s", "echo: hello", string(body))
+		}
+
+		cassette, err := ioutil.ReadFile(cassettePath)
+		if err != nil {
+			t.Fatalf("expected cassette to be written: %v", err)
+		}
+
+		if !strings.Contains(string(cassette), "[REDACTED]") {
+			t.Errorf("expected the client's Authorization header to be redacted in the cassette, got: %s", string(cassette))
+		}
+		if strings.Contains(string(cassette), "secret-token") {
+			t.Errorf("expected the raw Authorization value not to be persisted, got: %s", string(cassette))
+		}
+	})
+
+	t.Run("records distinct interactions for distinct request bodies on the same running server", func(t *testing.T) {
+		server := webmock.New()
+		server.Start()
+
+		if err := server.Record(upstream.URL, filepath.Join(dir, "bodies.yml")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		first, err := http.Post(server.URL()+"/orders", "application/json", bytes.NewBufferString(`{"id":1}`))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		firstBody, _ := ioutil.ReadAll(first.Body)
+		first.Body.Close()
+
+		second, err := http.Post(server.URL()+"/orders", "application/json", bytes.NewBufferString(`{"id":2}`))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		secondBody, _ := ioutil.ReadAll(second.Body)
+		second.Body.Close()
+
+		if string(firstBody) == string(secondBody) {
+			t.Errorf("expected distinct request bodies to be proxied and recorded independently, both got: %s", string(firstBody))
+		}
+		if string(secondBody) != `echo: {"id":2}` {
+			t.Errorf("unexpected body for the second, distinct request, want: %s, got: %s", `echo: {"id":2}`, string(secondBody))
+		}
+	})
+
+	t.Run("replays from the cassette on the second run without contacting upstream", func(t *testing.T) {
+		server := webmock.New()
+		server.Start()
+
+		if err := server.Record("http://127.0.0.1:0", cassettePath); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		req, err := http.NewRequest("POST", server.URL()+"/proxy-me", bytes.NewBufferString("hello"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("unexpected status, want: %d, got: %d", http.StatusOK, resp.StatusCode)
+		}
+
+		body, _ := ioutil.ReadAll(resp.Body)
+		if string(body) != "echo: hello" {
+			t.Errorf("unexpected body, want: %s, got: %s", "echo: hello", string(body))
+		}
+	})
+}
@@ -0,0 +1,121 @@
+package webmock
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sync/atomic"
+	"text/template"
+	"time"
+)
+
+// Response is a canned status/body/headers triple, used with WithSequence
+// to cycle through several responses across successive matches.
+type Response struct {
+	Status  int
+	Body    string
+	Headers map[string]string
+}
+
+// WithTemplate renders body as a text/template, interpolating values
+// derived from the incoming request. The template context exposes
+// .Method, .Path, .Query (map[string]string), .Header (map[string]string),
+// .Body (raw request body) and .JSON (the body parsed as JSON, or nil).
+//
+// Templates are rendered with text/template, not html/template, so JSON
+// responses aren't HTML-escaped.
+func WithTemplate(tmpl string) Option {
+	t, err := template.New("stub").Parse(tmpl)
+	if err != nil {
+		panic(err)
+	}
+
+	return func(st *stub) {
+		st.tmpl = t
+	}
+}
+
+// WithDelay holds the response for d before writing it, honoring the
+// client's request context: if the request is canceled first, no
+// response is written.
+func WithDelay(d time.Duration) Option {
+	return func(st *stub) {
+		st.delay = d
+	}
+}
+
+// WithSequence cycles through responses on successive matches of the
+// stub, wrapping around once exhausted. Useful for scenario testing
+// (e.g. failing twice before succeeding).
+func WithSequence(responses ...Response) Option {
+	return func(st *stub) {
+		st.sequence = responses
+	}
+}
+
+// templateContext is the value exposed to a stub's response template.
+type templateContext struct {
+	Method string
+	Path   string
+	Query  map[string]string
+	Header map[string]string
+	Body   string
+	JSON   map[string]interface{}
+}
+
+func renderTemplate(tmpl *template.Template, r *http.Request) string {
+	var bodyBytes []byte
+	if r.Body != nil {
+		bodyBytes, _ = ioutil.ReadAll(r.Body)
+		r.Body.Close()
+		r.Body = ioutil.NopCloser(bytes.NewReader(bodyBytes))
+	}
+
+	ctx := templateContext{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Query:  flatten(r.URL.Query()),
+		Header: flattenHeader(r.Header),
+		Body:   string(bodyBytes),
+	}
+
+	var parsed map[string]interface{}
+	if json.Unmarshal(bodyBytes, &parsed) == nil {
+		ctx.JSON = parsed
+	}
+
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, ctx); err != nil {
+		return err.Error()
+	}
+	return out.String()
+}
+
+func flatten(values map[string][]string) map[string]string {
+	out := make(map[string]string, len(values))
+	for k, vs := range values {
+		if len(vs) > 0 {
+			out[k] = vs[0]
+		}
+	}
+	return out
+}
+
+func flattenHeader(header http.Header) map[string]string {
+	out := make(map[string]string, len(header))
+	for k, vs := range header {
+		if len(vs) > 0 {
+			out[k] = vs[0]
+		}
+	}
+	return out
+}
+
+// atomicNext returns the next index in [0, n) for a stub's response
+// sequence, advancing counter atomically so concurrent requests each get
+// a distinct slot.
+func atomicNext(counter *int64, n int64) int64 {
+	v := atomic.AddInt64(counter, 1) - 1
+	return v % n
+}
@@ -0,0 +1,104 @@
+package webmock_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/hlcfan/webmock"
+)
+
+func TestServerWithTemplate(t *testing.T) {
+	server := webmock.New()
+	server.Start()
+
+	server.Stub(
+		"POST", "/echo", "",
+		webmock.WithTemplate(`{"echo":"{{.Query.name}}","name":"{{.JSON.name}}"}`),
+	)
+
+	query := url.Values{"name": {"<b>Alex</b>"}}
+	req, err := http.NewRequest("POST", server.URL()+"/echo?"+query.Encode(), bytes.NewBufferString(`{"name":"Alex"}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	want := `{"echo":"<b>Alex</b>","name":"Alex"}`
+	if string(body) != want {
+		t.Errorf("unexpected templated body, want: %s, got: %s", want, string(body))
+	}
+}
+
+func TestServerWithDelay(t *testing.T) {
+	server := webmock.New()
+	server.Start()
+
+	server.Stub("GET", "/slow", "done", webmock.WithDelay(200*time.Millisecond))
+
+	t.Run("honors client cancellation before the delay elapses", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, "GET", server.URL()+"/slow", nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		_, err = http.DefaultClient.Do(req)
+		if err == nil {
+			t.Errorf("expected request to be canceled before the delayed response")
+		}
+	})
+
+	t.Run("responds after the delay when not canceled", func(t *testing.T) {
+		start := time.Now()
+		resp, err := http.Get(server.URL() + "/slow")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+			t.Errorf("expected response to be delayed at least 200ms, took: %s", elapsed)
+		}
+
+		body, _ := ioutil.ReadAll(resp.Body)
+		if string(body) != "done" {
+			t.Errorf("unexpected body, want: %s, got: %s", "done", string(body))
+		}
+	})
+}
+
+func TestServerWithSequence(t *testing.T) {
+	server := webmock.New()
+	server.Start()
+
+	server.Stub("GET", "/status", "", webmock.WithSequence(
+		webmock.Response{Status: http.StatusServiceUnavailable, Body: "not ready"},
+		webmock.Response{Status: http.StatusServiceUnavailable, Body: "not ready"},
+		webmock.Response{Status: http.StatusOK, Body: "ready"},
+	))
+
+	wantStatuses := []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK, http.StatusServiceUnavailable}
+	for i, wantStatus := range wantStatuses {
+		resp, err := http.Get(server.URL() + "/status")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.StatusCode != wantStatus {
+			t.Errorf("call %d: unexpected status, want: %d, got: %d", i, wantStatus, resp.StatusCode)
+		}
+		resp.Body.Close()
+	}
+}
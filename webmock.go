@@ -0,0 +1,198 @@
+// Package webmock provides a lightweight HTTP stub server for tests,
+// similar in spirit to WebMock/VCR: register stubs for method+path (and
+// optionally query/headers), point your client at the server's URL, and
+// assert on the responses it serves.
+package webmock
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// stub represents a single registered request/response pairing.
+type stub struct {
+	method  string
+	path    string
+	query   url.Values
+	headers map[string][]string
+
+	pathRegex   *regexp.Regexp
+	queryRegex  map[string]*regexp.Regexp
+	headerRegex map[string]*regexp.Regexp
+	jsonBody    map[string]interface{}
+
+	reqBody    string
+	hasReqBody bool
+
+	priority int
+	seq      int64
+
+	status int
+	body   string
+	header map[string]string
+
+	tmpl     *template.Template
+	delay    time.Duration
+	sequence []Response
+	seqIdx   int64
+}
+
+// Option customizes a stub registered via Server.Stub.
+type Option func(*stub)
+
+// Server is an HTTP test server backed by a set of stubs. Unmatched
+// requests are answered with 404, unless Server.Record is configured to
+// proxy them to an upstream.
+type Server struct {
+	mu    sync.Mutex
+	stubs []*stub
+
+	requestsMu sync.Mutex
+	requests   []recordedRequest
+
+	expMu        sync.Mutex
+	expectations []*expectation
+
+	httpServer *httptest.Server
+	recorder   *recorder
+}
+
+// New creates a Server bound to a local address. Call Start to begin
+// serving.
+func New() *Server {
+	s := &Server{}
+	s.httpServer = httptest.NewUnstartedServer(http.HandlerFunc(s.ServeHTTP))
+	return s
+}
+
+// Start starts the underlying httptest.Server.
+func (s *Server) Start() {
+	s.httpServer.Start()
+}
+
+// URL returns the base URL of the server. It is available as soon as New
+// returns, before Start is called.
+func (s *Server) URL() string {
+	return "http://" + s.httpServer.Listener.Addr().String()
+}
+
+// Reset removes all registered stubs.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stubs = nil
+}
+
+// Stub registers a response for requests matching method and urlPath.
+// urlPath may include a query string (e.g. "/get?foo=bar"), in which case
+// the query parameters must match exactly for the stub to apply.
+func (s *Server) Stub(method, urlPath, body string, opts ...Option) {
+	parsed, err := url.Parse(urlPath)
+	if err != nil {
+		panic(err)
+	}
+
+	st := &stub{
+		method: strings.ToUpper(method),
+		path:   parsed.Path,
+		query:  parsed.Query(),
+		status: http.StatusOK,
+		body:   body,
+	}
+
+	for _, opt := range opts {
+		opt(st)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st.seq = int64(len(s.stubs))
+	s.stubs = append(s.stubs, st)
+}
+
+// addStub registers an already-built stub, used internally by cassette
+// loading so it can construct stubs without going through Stub's
+// string-based option parsing.
+func (s *Server) addStub(st *stub) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st.seq = int64(len(s.stubs))
+	s.stubs = append(s.stubs, st)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.recordRequest(r)
+
+	st := s.match(r)
+	if st == nil {
+		if s.tryRecord(w, r) {
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if st.delay > 0 {
+		select {
+		case <-time.After(st.delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	status, body, headers := st.response(r)
+
+	for k, v := range headers {
+		w.Header().Set(k, v)
+	}
+
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	w.Write([]byte(body))
+}
+
+// response resolves the status, body and headers a stub should answer r
+// with, applying a response sequence and/or request templating if
+// configured.
+func (st *stub) response(r *http.Request) (int, string, map[string]string) {
+	status, body, headers := st.status, st.body, st.header
+
+	if len(st.sequence) > 0 {
+		idx := atomicNext(&st.seqIdx, int64(len(st.sequence)))
+		resp := st.sequence[idx]
+		status, body, headers = resp.Status, resp.Body, resp.Headers
+	}
+
+	if st.tmpl != nil {
+		body = renderTemplate(st.tmpl, r)
+	}
+
+	return status, body, headers
+}
+
+// match returns the best matching stub for r, preferring higher priority,
+// then more specific (more constrained) stubs, then earlier registration.
+func (s *Server) match(r *http.Request) *stub {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var best *stub
+	for _, st := range s.stubs {
+		if !matchStub(st, r) {
+			continue
+		}
+		if best == nil || st.moreSpecificThan(best) {
+			best = st
+		}
+	}
+	return best
+}